@@ -0,0 +1,123 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package analytics derives simple technical indicators (moving average,
+// return, volatility, max drawdown) from a chronologically ordered series
+// of closing prices. It has no knowledge of providers or HTTP so it can be
+// tested independently of the data fetched from any upstream API.
+package analytics
+
+import (
+	"fmt"
+	"math"
+)
+
+// SMA returns the simple moving average of the most recent window values
+// in closes. closes must be in chronological order (oldest first).
+func SMA(closes []float64, window int) (float64, error) {
+	recent, err := tail(closes, window)
+	if err != nil {
+		return 0, err
+	}
+	var sum float64
+	for _, c := range recent {
+		sum += c
+	}
+	return sum / float64(window), nil
+}
+
+// ReturnPct returns the percent change between the oldest and newest
+// close in the most recent window.
+func ReturnPct(closes []float64, window int) (float64, error) {
+	recent, err := tail(closes, window)
+	if err != nil {
+		return 0, err
+	}
+	first, last := recent[0], recent[len(recent)-1]
+	if first == 0 {
+		return 0, fmt.Errorf("first close in window is zero")
+	}
+	return (last - first) / first * 100, nil
+}
+
+// Volatility returns the standard deviation of the most recent window log
+// returns (so window+1 closes are required).
+func Volatility(closes []float64, window int) (float64, error) {
+	recent, err := tail(closes, window+1)
+	if err != nil {
+		return 0, err
+	}
+	return stddev(logReturns(recent)), nil
+}
+
+// MaxDrawdown returns the largest peak-to-trough percentage decline over
+// the most recent window closes.
+func MaxDrawdown(closes []float64, window int) (float64, error) {
+	recent, err := tail(closes, window)
+	if err != nil {
+		return 0, err
+	}
+
+	peak := recent[0]
+	var maxDD float64
+	for _, c := range recent {
+		if c > peak {
+			peak = c
+		}
+		if peak == 0 {
+			continue
+		}
+		if dd := (peak - c) / peak; dd > maxDD {
+			maxDD = dd
+		}
+	}
+	return maxDD * 100, nil
+}
+
+// tail returns the last n values of closes, or an error if closes is too
+// short.
+func tail(closes []float64, n int) ([]float64, error) {
+	if len(closes) < n {
+		return nil, fmt.Errorf("need at least %d closes, got %d", n, len(closes))
+	}
+	return closes[len(closes)-n:], nil
+}
+
+// logReturns returns the log returns between consecutive closes.
+func logReturns(closes []float64) []float64 {
+	returns := make([]float64, 0, len(closes)-1)
+	for i := 1; i < len(closes); i++ {
+		if closes[i-1] <= 0 || closes[i] <= 0 {
+			continue
+		}
+		returns = append(returns, math.Log(closes[i]/closes[i-1]))
+	}
+	return returns
+}
+
+// stddev returns the population standard deviation of xs.
+func stddev(xs []float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	var mean float64
+	for _, x := range xs {
+		mean += x
+	}
+	mean /= float64(len(xs))
+
+	var sumSq float64
+	for _, x := range xs {
+		d := x - mean
+		sumSq += d * d
+	}
+	return math.Sqrt(sumSq / float64(len(xs)))
+}
@@ -0,0 +1,101 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package analytics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSMA(t *testing.T) {
+	closes := []float64{1, 2, 3, 4, 5}
+	got, err := SMA(closes, 3)
+	if err != nil {
+		t.Fatalf("SMA returned error: %v", err)
+	}
+	if want := 4.0; got != want {
+		t.Errorf("SMA() = %v, want %v", got, want)
+	}
+
+	if _, err := SMA(closes, 10); err == nil {
+		t.Error("SMA() with too few closes: want error, got nil")
+	}
+}
+
+func TestReturnPct(t *testing.T) {
+	closes := []float64{100, 90, 110}
+	got, err := ReturnPct(closes, 3)
+	if err != nil {
+		t.Fatalf("ReturnPct returned error: %v", err)
+	}
+	if want := 10.0; got != want {
+		t.Errorf("ReturnPct() = %v, want %v", got, want)
+	}
+}
+
+func TestVolatilityConstantSeriesIsZero(t *testing.T) {
+	closes := []float64{100, 100, 100, 100}
+	got, err := Volatility(closes, 3)
+	if err != nil {
+		t.Fatalf("Volatility returned error: %v", err)
+	}
+	if got != 0 {
+		t.Errorf("Volatility() of a constant series = %v, want 0", got)
+	}
+}
+
+func TestVolatilityNotConstant(t *testing.T) {
+	closes := []float64{100, 110, 100, 110}
+	got, err := Volatility(closes, 3)
+	if err != nil {
+		t.Fatalf("Volatility returned error: %v", err)
+	}
+	if got <= 0 || math.IsNaN(got) {
+		t.Errorf("Volatility() = %v, want a positive number", got)
+	}
+}
+
+func TestMaxDrawdown(t *testing.T) {
+	closes := []float64{100, 120, 90, 95}
+	got, err := MaxDrawdown(closes, 4)
+	if err != nil {
+		t.Fatalf("MaxDrawdown returned error: %v", err)
+	}
+	want := 25.0 // Peak 120 -> trough 90.
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("MaxDrawdown() = %v, want %v", got, want)
+	}
+}
+
+func TestMaxDrawdownNoDecline(t *testing.T) {
+	closes := []float64{100, 105, 110}
+	got, err := MaxDrawdown(closes, 3)
+	if err != nil {
+		t.Fatalf("MaxDrawdown returned error: %v", err)
+	}
+	if got != 0 {
+		t.Errorf("MaxDrawdown() of a monotonically increasing series = %v, want 0", got)
+	}
+}
+
+func TestTooFewCloses(t *testing.T) {
+	closes := []float64{1, 2}
+	if _, err := ReturnPct(closes, 5); err == nil {
+		t.Error("ReturnPct() with too few closes: want error, got nil")
+	}
+	if _, err := Volatility(closes, 5); err == nil {
+		t.Error("Volatility() with too few closes: want error, got nil")
+	}
+	if _, err := MaxDrawdown(closes, 5); err == nil {
+		t.Error("MaxDrawdown() with too few closes: want error, got nil")
+	}
+}
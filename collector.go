@@ -12,6 +12,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/url"
@@ -21,17 +22,37 @@ import (
 	"github.com/kofalt/go-memoize"
 	"github.com/prometheus/client_golang/prometheus"
 
-	"github.com/marcopaganini/quotes-exporter/stonks"
-	"github.com/marcopaganini/quotes-exporter/twelvedata"
+	"github.com/marcopaganini/quotes-exporter/config"
+	"github.com/marcopaganini/quotes-exporter/providers"
 )
 
+// errCacheMiss is returned by the no-op fetcher peekCache uses to probe the
+// cache without making an API call: Memoize only invokes the fetcher (and
+// never caches its result, since it returned an error) when the key isn't
+// already cached.
+var errCacheMiss = errors.New("cache miss")
+
+// defaultProviderPrefix is the provider prefix used for symbols with no
+// "prefix:" in front of them, kept for compatibility with existing scrape
+// configs.
+const defaultProviderPrefix = "tw"
+
+// providerPrefixes maps the short prefix used in the "symbols" query
+// parameter (e.g. "tw:AAPL") to the name a provider is registered under.
+var providerPrefixes = map[string]string{
+	"tw": "twelvedata",
+	"st": "stonks",
+}
+
 var (
-	// These are metrics for the collector itself
-	queryDuration = prometheus.NewSummary(
+	// These are metrics for the collector itself.
+	queryDuration = prometheus.NewSummaryVec(
 		prometheus.SummaryOpts{
-			Name: "quotes_exporter_query_duration_seconds",
-			Help: "Duration of queries to the upstream API",
+			Name:       "quotes_exporter_query_duration_seconds",
+			Help:       "Duration of queries to the upstream API.",
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
 		},
+		[]string{"provider"},
 	)
 	queryCount = prometheus.NewCounter(
 		prometheus.CounterOpts{
@@ -45,16 +66,63 @@ var (
 			Help: "Count of failed queries",
 		},
 	)
+	cacheHitsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "quotes_exporter_cache_hits_total",
+			Help: "Count of quote lookups served from the cache.",
+		},
+	)
+	cacheMissesTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "quotes_exporter_cache_misses_total",
+			Help: "Count of quote lookups that required an upstream request.",
+		},
+	)
+	cacheEntries = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "quotes_exporter_cache_entries",
+			Help: "Number of entries currently held in the quote cache.",
+		},
+		func() float64 { return float64(cache.Storage.ItemCount()) },
+	)
+	symbolsRequestedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "quotes_exporter_symbols_requested_total",
+			Help: "Count of symbols requested from each provider, by provider.",
+		},
+		[]string{"provider"},
+	)
 
-	// Cache external API consuming calls for 10 minutes.
-	cache *memoize.Memoizer = memoize.NewMemoizer(10*time.Minute, 20*time.Minute)
+	// staticCollectors are the collectors that stay the same across
+	// scrapes, as opposed to the per-request collector/historyCollector.
+	// Each handler registers them alongside its request-specific
+	// collector.
+	staticCollectors []prometheus.Collector
+
+	// cache memoizes external API calls; initCache replaces it once the
+	// config file (if any) and flags have been resolved in main().
+	cache *memoize.Memoizer = memoize.NewMemoizer(config.Default().Cache.TTL, config.Default().Cache.Purge)
 
 	// flags
 	flagPort             int
-	flagEnableTwelvedata bool
 	flagTwelvedataApiKey string
+	flagBatchSize        int
+	flagConfigPath       string
 )
 
+func init() {
+	staticCollectors = []prometheus.Collector{
+		queryDuration, queryCount, errorCount,
+		cacheHitsTotal, cacheMissesTotal, cacheEntries, symbolsRequestedTotal,
+	}
+}
+
+// initCache replaces the package-level cache with one using the TTL and
+// purge interval from cfg.
+func initCache(cfg config.Config) {
+	cache = memoize.NewMemoizer(cfg.Cache.TTL, cfg.Cache.Purge)
+}
+
 // collector holds data for a prometheus collector.
 type collector struct {
 	symbols []string
@@ -65,6 +133,8 @@ func newCollector(myurl *url.URL) (collector, error) {
 	var symbols []string
 
 	// Typical query is formatted as: ?symbols=AAA,BBB...&symbols=CCC,DDD...
+	// Each symbol may be prefixed with a provider short name (e.g.
+	// "tw:AAPL"); unprefixed symbols fall back to defaultProviderPrefix.
 	// We fetch all symbols into a single slice.
 	qvalues, ok := myurl.Query()["symbols"]
 	if !ok {
@@ -76,63 +146,366 @@ func newCollector(myurl *url.URL) (collector, error) {
 	return collector{symbols}, nil
 }
 
+// splitSymbol splits a "prefix:symbol" entry into the provider name
+// registered for that prefix and the bare symbol. Entries with no prefix
+// use defaultProviderPrefix.
+func splitSymbol(symbol string) (providerName string, bareSymbol string, err error) {
+	prefix := defaultProviderPrefix
+	bareSymbol = symbol
+	if idx := strings.Index(symbol, ":"); idx >= 0 {
+		prefix, bareSymbol = symbol[:idx], symbol[idx+1:]
+	}
+	providerName, ok := providerPrefixes[prefix]
+	if !ok {
+		return "", "", fmt.Errorf("unknown provider prefix %q in symbol %q", prefix, symbol)
+	}
+	return providerName, bareSymbol, nil
+}
+
 // Describe outputs description for prometheus timeseries.
 func (c collector) Describe(ch chan<- *prometheus.Desc) {
 	// Must send one description, or the registry panics.
 	ch <- prometheus.NewDesc("dummy", "dummy", nil, nil)
 }
 
+// symbolEntry pairs a symbol as it appeared in the request (possibly
+// "prefix:SYMBOL") with the bare symbol passed to the provider.
+type symbolEntry struct {
+	symbol, bareSymbol string
+}
+
+// quoteResult is a fetched quote plus whether it came from the cache, used
+// purely for logging.
+type quoteResult struct {
+	quote  providers.Quote
+	cached bool
+}
+
 // Collect retrieves quote data and ouputs prometheus compatible timeseries on
 // the output channel.
 func (c collector) Collect(ch chan<- prometheus.Metric) {
 	queryCount.Inc()
 
+	// Group symbols by provider so each provider's uncached symbols are
+	// fetched in batch requests of up to flagBatchSize symbols each
+	// instead of one request per symbol.
+	groups := map[string][]symbolEntry{}
+	var providerOrder []string
 	for _, symbol := range c.symbols {
-		// Try not to hit the end point too hard.
-		cachedFetcher := func() (interface{}, error) {
-
-			if flagEnableTwelvedata {
-				// Check if the environment variable exists
-				if flagTwelvedataApiKey == "" {
-					fmt.Println("Environment variable 'TWELVEDATA_API_KEY' is not set.")
-					return nil, nil
-				}
-				return twelvedata.Quote(symbol, flagTwelvedataApiKey)
-			}
-			return stonks.Quote(symbol)
+		providerName, bareSymbol, err := splitSymbol(symbol)
+		if err != nil {
+			errorCount.Inc()
+			log.Printf("%v\n", err)
+			continue
+		}
+		if _, ok := groups[providerName]; !ok {
+			providerOrder = append(providerOrder, providerName)
+		}
+		groups[providerName] = append(groups[providerName], symbolEntry{symbol, bareSymbol})
+		symbolsRequestedTotal.WithLabelValues(providerName).Inc()
+	}
+
+	for _, providerName := range providerOrder {
+		entries := groups[providerName]
+
+		provider, err := providers.Get(providerName)
+		if err != nil {
+			errorCount.Inc()
+			log.Printf("%v\n", err)
+			continue
 		}
 
 		start := time.Now()
-		qret, err, cached := cache.Memoize(symbol, cachedFetcher)
-		queryDuration.Observe(float64(time.Since(start).Seconds()))
+		quotes := c.fetchQuotes(provider, entries)
+		queryDuration.WithLabelValues(providerName).Observe(float64(time.Since(start).Seconds()))
+
+		// Fetch full quotes for the whole provider group up front (batched
+		// when the provider supports it) rather than one unbatched call per
+		// symbol inside the loop below, so one slow/rate-limited provider
+		// can't serialize N full-quote requests into the same scrape.
+		var fullQuotes map[string]providers.FullQuote
+		_, hasSingle := provider.(providers.FullQuoteProvider)
+		_, hasBatch := provider.(providers.FullQuoteBatchProvider)
+		if hasSingle || hasBatch {
+			fullQuotes = c.fetchFullQuotes(provider, entries)
+		}
+
+		for _, e := range entries {
+			qr, ok := quotes[e.symbol]
+			if !ok {
+				// Error already logged by fetchQuotes.
+				continue
+			}
+
+			// ls contains the list of labels and lvs the corresponding values.
+			ls := []string{"symbol", "source"}
+			lvs := []string{e.bareSymbol, providerName}
 
+			cachedSuffix := ""
+			if qr.cached {
+				cachedSuffix = " (cached)"
+			}
+			log.Printf("Retrieved %s%s from %s, price: %f\n", e.bareSymbol, cachedSuffix, providerName, qr.quote.Price)
+
+			ch <- prometheus.MustNewConstMetric(
+				prometheus.NewDesc("quotes_exporter_price", "Asset Price.", ls, nil),
+				prometheus.GaugeValue,
+				qr.quote.Price,
+				lvs...,
+			)
+
+			if fq, ok := fullQuotes[e.symbol]; ok {
+				emitFullQuoteGauges(ch, fq, e.bareSymbol)
+			}
+		}
+	}
+}
+
+// fetchQuotes resolves a quote for every entry, serving cached values
+// first and batching the rest into as few provider requests as possible.
+func (c collector) fetchQuotes(provider providers.Provider, entries []symbolEntry) map[string]quoteResult {
+	result := make(map[string]quoteResult, len(entries))
+
+	var missBare, missSymbol []string
+	for _, e := range entries {
+		if q, ok := c.peekCache(e.symbol); ok {
+			cacheHitsTotal.Inc()
+			result[e.symbol] = quoteResult{quote: q, cached: true}
+			continue
+		}
+		cacheMissesTotal.Inc()
+		missBare = append(missBare, e.bareSymbol)
+		missSymbol = append(missSymbol, e.symbol)
+	}
+	if len(missBare) == 0 {
+		return result
+	}
+
+	fetched := c.batchFetch(provider, missBare)
+	for i, bare := range missBare {
+		symbol := missSymbol[i]
+		q, ok := fetched[strings.ToUpper(bare)]
+		if !ok {
+			errorCount.Inc()
+			log.Printf("No quote returned for %s\n", bare)
+			continue
+		}
+		cacheStore(symbol, q)
+		result[symbol] = quoteResult{quote: q}
+	}
+	return result
+}
+
+// peekCache reports whether symbol already has a cached quote, without
+// making any upstream request.
+func (c collector) peekCache(symbol string) (providers.Quote, bool) {
+	qret, err, cached := cache.Memoize(symbol, func() (interface{}, error) {
+		return nil, errCacheMiss
+	})
+	if !cached || err != nil {
+		return providers.Quote{}, false
+	}
+	q, ok := qret.(providers.Quote)
+	return q, ok
+}
+
+// cacheStore caches q under symbol for the memoizer's configured TTL.
+func cacheStore(symbol string, q providers.Quote) {
+	cache.Memoize(symbol, func() (interface{}, error) {
+		return q, nil
+	})
+}
+
+// batchFetch looks up bareSymbols against provider, using QuoteBatch (in
+// chunks of flagBatchSize) when provider supports it, falling back to one
+// request per symbol otherwise. The returned map is keyed by uppercased
+// symbol; symbols that failed to resolve are simply absent.
+func (c collector) batchFetch(provider providers.Provider, bareSymbols []string) map[string]providers.Quote {
+	bp, ok := provider.(providers.BatchProvider)
+	if !ok {
+		return sequentialFetch(provider, bareSymbols)
+	}
+
+	batchSize := flagBatchSize
+	if batchSize <= 0 {
+		batchSize = config.DefaultBatchSize
+	}
+
+	result := make(map[string]providers.Quote, len(bareSymbols))
+	for i := 0; i < len(bareSymbols); i += batchSize {
+		end := i + batchSize
+		if end > len(bareSymbols) {
+			end = len(bareSymbols)
+		}
+		batch, err := bp.QuoteBatch(bareSymbols[i:end])
+		if err != nil {
+			errorCount.Inc()
+			log.Printf("Error fetching quote batch: %v\n", err)
+			continue
+		}
+		for symbol, q := range batch {
+			result[symbol] = q
+		}
+	}
+	return result
+}
+
+// sequentialFetch looks up each symbol individually, for providers that
+// don't implement providers.BatchProvider.
+func sequentialFetch(provider providers.Provider, bareSymbols []string) map[string]providers.Quote {
+	result := make(map[string]providers.Quote, len(bareSymbols))
+	for _, s := range bareSymbols {
+		q, err := provider.Quote(s)
 		if err != nil {
 			errorCount.Inc()
-			log.Printf("Error looking up %s: %v\n", symbol, err)
-			return
+			log.Printf("Error looking up %s: %v\n", s, err)
+			continue
 		}
-		// Convert to native type as Memoize returns an interface.
-		price, ok := qret.(float64)
+		result[strings.ToUpper(s)] = q
+	}
+	return result
+}
+
+// fetchFullQuotes resolves the richer quote for every entry that wants one,
+// serving cached values first (wired into cacheHitsTotal/cacheMissesTotal
+// like fetchQuotes) and batching the rest into as few provider requests as
+// possible.
+func (c collector) fetchFullQuotes(provider providers.Provider, entries []symbolEntry) map[string]providers.FullQuote {
+	result := make(map[string]providers.FullQuote, len(entries))
+
+	var missBare, missSymbol []string
+	for _, e := range entries {
+		if fq, ok := c.peekFullQuoteCache(e.symbol); ok {
+			cacheHitsTotal.Inc()
+			result[e.symbol] = fq
+			continue
+		}
+		cacheMissesTotal.Inc()
+		missBare = append(missBare, e.bareSymbol)
+		missSymbol = append(missSymbol, e.symbol)
+	}
+	if len(missBare) == 0 {
+		return result
+	}
+
+	fetched := c.batchFetchFullQuotes(provider, missBare)
+	for i, bare := range missBare {
+		symbol := missSymbol[i]
+		fq, ok := fetched[strings.ToUpper(bare)]
 		if !ok {
 			errorCount.Inc()
-			log.Printf("Invalid quote data for %s: %v\n", symbol, qret)
-			return
+			log.Printf("No full quote returned for %s\n", bare)
+			continue
 		}
+		fullQuoteCacheStore(symbol, fq)
+		result[symbol] = fq
+	}
+	return result
+}
 
-		// ls contains the list of labels and lvs the corresponding values.
-		ls := []string{"symbol", "name"}
-		lvs := []string{symbol, symbol}
+// peekFullQuoteCache reports whether symbol already has a cached full
+// quote, without making any upstream request.
+func (c collector) peekFullQuoteCache(symbol string) (providers.FullQuote, bool) {
+	qret, err, cached := cache.Memoize("full:"+symbol, func() (interface{}, error) {
+		return nil, errCacheMiss
+	})
+	if !cached || err != nil {
+		return providers.FullQuote{}, false
+	}
+	fq, ok := qret.(providers.FullQuote)
+	return fq, ok
+}
+
+// fullQuoteCacheStore caches fq under symbol for the memoizer's configured
+// TTL.
+func fullQuoteCacheStore(symbol string, fq providers.FullQuote) {
+	cache.Memoize("full:"+symbol, func() (interface{}, error) {
+		return fq, nil
+	})
+}
 
-		c := ""
-		if cached {
-			c = " (cached)"
+// batchFetchFullQuotes looks up bareSymbols against provider, using
+// FullQuoteBatch (in chunks of flagBatchSize) when provider supports it,
+// falling back to one FullQuote request per symbol otherwise. The returned
+// map is keyed by uppercased symbol; symbols that failed to resolve are
+// simply absent.
+func (c collector) batchFetchFullQuotes(provider providers.Provider, bareSymbols []string) map[string]providers.FullQuote {
+	bp, ok := provider.(providers.FullQuoteBatchProvider)
+	if !ok {
+		fqProvider, ok := provider.(providers.FullQuoteProvider)
+		if !ok {
+			return nil
 		}
-		log.Printf("Retrieved %s%s, price: %f\n", symbol, c, price)
+		return sequentialFullQuoteFetch(fqProvider, bareSymbols)
+	}
 
+	batchSize := flagBatchSize
+	if batchSize <= 0 {
+		batchSize = config.DefaultBatchSize
+	}
+
+	result := make(map[string]providers.FullQuote, len(bareSymbols))
+	for i := 0; i < len(bareSymbols); i += batchSize {
+		end := i + batchSize
+		if end > len(bareSymbols) {
+			end = len(bareSymbols)
+		}
+		batch, err := bp.FullQuoteBatch(bareSymbols[i:end])
+		if err != nil {
+			errorCount.Inc()
+			log.Printf("Error fetching full quote batch: %v\n", err)
+			continue
+		}
+		for symbol, fq := range batch {
+			result[symbol] = fq
+		}
+	}
+	return result
+}
+
+// sequentialFullQuoteFetch looks up each symbol individually, for providers
+// that implement providers.FullQuoteProvider but not
+// providers.FullQuoteBatchProvider.
+func sequentialFullQuoteFetch(provider providers.FullQuoteProvider, bareSymbols []string) map[string]providers.FullQuote {
+	result := make(map[string]providers.FullQuote, len(bareSymbols))
+	for _, s := range bareSymbols {
+		fq, err := provider.FullQuote(s)
+		if err != nil {
+			errorCount.Inc()
+			log.Printf("Error fetching full quote for %s: %v\n", s, err)
+			continue
+		}
+		result[strings.ToUpper(s)] = fq
+	}
+	return result
+}
+
+// emitFullQuoteGauges sends the full-quote gauges for bareSymbol on ch.
+func emitFullQuoteGauges(ch chan<- prometheus.Metric, fq providers.FullQuote, bareSymbol string) {
+	// ls/lvs follow the labeling pattern used by mature Prometheus
+	// exporters, so users can group or alert by exchange or currency.
+	ls := []string{"symbol", "name", "exchange", "currency"}
+	lvs := []string{bareSymbol, fq.Name, fq.Exchange, fq.Currency}
+
+	gauges := []struct {
+		name string
+		help string
+		val  float64
+	}{
+		{"quotes_exporter_open", "Opening price.", fq.Open},
+		{"quotes_exporter_high", "Highest price of the day.", fq.High},
+		{"quotes_exporter_low", "Lowest price of the day.", fq.Low},
+		{"quotes_exporter_volume", "Trading volume.", fq.Volume},
+		{"quotes_exporter_change_percent", "Percent change since previous close.", fq.PercentChange},
+		{"quotes_exporter_previous_close", "Previous close price.", fq.PreviousClose},
+		{"quotes_exporter_fifty_two_week_high", "52-week high price.", fq.FiftyTwoWeekHigh},
+		{"quotes_exporter_fifty_two_week_low", "52-week low price.", fq.FiftyTwoWeekLow},
+	}
+	for _, g := range gauges {
 		ch <- prometheus.MustNewConstMetric(
-			prometheus.NewDesc("quotes_exporter_price", "Asset Price.", ls, nil),
+			prometheus.NewDesc(g.name, g.help, ls, nil),
 			prometheus.GaugeValue,
-			price,
+			g.val,
 			lvs...,
 		)
 	}
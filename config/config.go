@@ -0,0 +1,138 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package config loads the exporter's optional YAML configuration file,
+// modeled after the debug/prometheus block used by Docker Registry's
+// configuration.yml. A config file is never required: flags and built-in
+// defaults work on their own, and any flag passed on the command line
+// overrides the matching value loaded from the file.
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPort is the port the exporter listens on when neither the config
+// file nor -port specify one.
+const DefaultPort = 9393
+
+// DefaultPath is the metrics endpoint path used when the config file
+// doesn't set one.
+const DefaultPath = "/quotes"
+
+// DefaultBatchSize is the default cap on symbols per batch quote request.
+const DefaultBatchSize = 120
+
+// CacheConfig tunes how long fetched quotes are memoized.
+type CacheConfig struct {
+	TTL   time.Duration `yaml:"ttl"`
+	Purge time.Duration `yaml:"purge"`
+}
+
+// ProviderConfig holds the per-provider settings read from the
+// "providers:" block of the config file.
+type ProviderConfig struct {
+	APIKey    string  `yaml:"api_key"`
+	RateLimit float64 `yaml:"rate_limit"`
+	Enabled   bool    `yaml:"enabled"`
+}
+
+// Config is the top-level shape of the YAML configuration file.
+type Config struct {
+	Port      int                       `yaml:"port"`
+	Path      string                    `yaml:"path"`
+	BatchSize int                       `yaml:"batch_size"`
+	Cache     CacheConfig               `yaml:"cache"`
+	Providers map[string]ProviderConfig `yaml:"providers"`
+}
+
+// rawProviderConfig mirrors ProviderConfig with pointer fields, so decoding
+// can tell a field the file left out from one explicitly set to its zero
+// value. yaml.Unmarshal can't merge into Default()'s provider map directly:
+// map values aren't addressable, so decoding would replace each mentioned
+// provider's whole entry rather than overlaying just the fields present in
+// the file.
+type rawProviderConfig struct {
+	APIKey    *string  `yaml:"api_key"`
+	RateLimit *float64 `yaml:"rate_limit"`
+	Enabled   *bool    `yaml:"enabled"`
+}
+
+// rawConfig decodes just the providers block of the YAML file, for the
+// field-by-field overlay Load does onto Default()'s provider entries.
+type rawConfig struct {
+	Providers map[string]rawProviderConfig `yaml:"providers"`
+}
+
+// Default returns the exporter's built-in configuration, used whenever no
+// -config file is given.
+func Default() Config {
+	return Config{
+		Port:      DefaultPort,
+		Path:      DefaultPath,
+		BatchSize: DefaultBatchSize,
+		Cache: CacheConfig{
+			TTL:   10 * time.Minute,
+			Purge: 20 * time.Minute,
+		},
+		Providers: map[string]ProviderConfig{
+			"twelvedata": {Enabled: true},
+			"stonks":     {Enabled: true},
+		},
+	}
+}
+
+// Load reads and parses the YAML configuration file at path, starting
+// from Default() so that fields left unset in the file keep their
+// built-in value.
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("reading config %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+
+	// Providers needs its own pass: map values aren't addressable, so the
+	// yaml.Unmarshal above replaced each provider mentioned in the file
+	// with a brand-new ProviderConfig instead of overlaying it onto
+	// Default()'s entry, silently resetting any field the file left out
+	// (e.g. a file that only sets api_key would flip enabled back to
+	// false). Re-decode the providers block with pointer fields so we can
+	// tell "absent" from "explicitly zero" and overlay by hand.
+	var raw rawConfig
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return Config{}, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	defaults := Default().Providers
+	for name, rp := range raw.Providers {
+		pc := defaults[name]
+		if rp.APIKey != nil {
+			pc.APIKey = *rp.APIKey
+		}
+		if rp.RateLimit != nil {
+			pc.RateLimit = *rp.RateLimit
+		}
+		if rp.Enabled != nil {
+			pc.Enabled = *rp.Enabled
+		}
+		cfg.Providers[name] = pc
+	}
+
+	return cfg, nil
+}
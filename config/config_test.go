@@ -0,0 +1,77 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("writing test config: %v", err)
+	}
+	return path
+}
+
+func TestLoadPartialProviderOverrideKeepsDefaults(t *testing.T) {
+	path := writeConfig(t, "providers:\n  twelvedata:\n    api_key: XYZ\n")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+
+	pc := cfg.Providers["twelvedata"]
+	if pc.APIKey != "XYZ" {
+		t.Errorf("Providers[twelvedata].APIKey = %q, want %q", pc.APIKey, "XYZ")
+	}
+	if !pc.Enabled {
+		t.Error("Providers[twelvedata].Enabled = false, want true (unset in file, should keep Default())")
+	}
+}
+
+func TestLoadExplicitDisableOverridesDefault(t *testing.T) {
+	path := writeConfig(t, "providers:\n  stonks:\n    enabled: false\n")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+
+	if cfg.Providers["stonks"].Enabled {
+		t.Error("Providers[stonks].Enabled = true, want false (explicitly disabled in file)")
+	}
+	if !cfg.Providers["twelvedata"].Enabled {
+		t.Error("Providers[twelvedata].Enabled = false, want true (untouched by file, should keep Default())")
+	}
+}
+
+func TestLoadUnsetFieldsKeepDefaults(t *testing.T) {
+	path := writeConfig(t, "providers:\n  twelvedata:\n    api_key: XYZ\n")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+
+	want := Default()
+	if cfg.Port != want.Port {
+		t.Errorf("Port = %v, want %v", cfg.Port, want.Port)
+	}
+	if cfg.Cache != want.Cache {
+		t.Errorf("Cache = %v, want %v", cfg.Cache, want.Cache)
+	}
+}
@@ -0,0 +1,193 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/marcopaganini/quotes-exporter/analytics"
+	"github.com/marcopaganini/quotes-exporter/providers"
+)
+
+const (
+	defaultHistoryInterval = "1day"
+	defaultHistoryWindow   = 30
+
+	// minSeriesOutputSize is the smallest number of bars ever requested
+	// from a provider, regardless of window. Requesting at least this many
+	// means repeated scrapes with different (small) windows over the same
+	// symbol tend to land on the same cache entry and share one upstream
+	// call; a larger window simply grows the requested (and cached) size.
+	minSeriesOutputSize = 90
+)
+
+// historyCollector serves SMA, return, volatility and max-drawdown gauges
+// derived from a symbol's historical time series.
+type historyCollector struct {
+	symbols  []string
+	interval string
+	window   int
+}
+
+// newHistoryCollector returns a new historyCollector parsed from the URL
+// used to scrape /history (symbols, interval, window).
+func newHistoryCollector(myurl *url.URL) (historyCollector, error) {
+	var symbols []string
+
+	qvalues, ok := myurl.Query()["symbols"]
+	if !ok {
+		return historyCollector{}, fmt.Errorf("missing symbols in query")
+	}
+	for _, qvalue := range qvalues {
+		symbols = append(symbols, strings.Split(qvalue, ",")...)
+	}
+
+	interval := myurl.Query().Get("interval")
+	if interval == "" {
+		interval = defaultHistoryInterval
+	}
+
+	window := defaultHistoryWindow
+	if w := myurl.Query().Get("window"); w != "" {
+		parsed, err := strconv.Atoi(w)
+		if err != nil {
+			return historyCollector{}, fmt.Errorf("invalid window %q: %w", w, err)
+		}
+		window = parsed
+	}
+
+	return historyCollector{symbols, interval, window}, nil
+}
+
+// Describe outputs description for prometheus timeseries.
+func (c historyCollector) Describe(ch chan<- *prometheus.Desc) {
+	// Must send one description, or the registry panics.
+	ch <- prometheus.NewDesc("dummy", "dummy", nil, nil)
+}
+
+// Collect fetches the historical series for each symbol and emits the
+// derived analytics gauges on ch.
+func (c historyCollector) Collect(ch chan<- prometheus.Metric) {
+	queryCount.Inc()
+
+	windowLabel := strconv.Itoa(c.window)
+
+	for _, symbol := range c.symbols {
+		providerName, bareSymbol, err := splitSymbol(symbol)
+		if err != nil {
+			errorCount.Inc()
+			log.Printf("%v\n", err)
+			continue
+		}
+
+		provider, err := providers.Get(providerName)
+		if err != nil {
+			errorCount.Inc()
+			log.Printf("%v\n", err)
+			continue
+		}
+
+		tsProvider, ok := provider.(providers.TimeSeriesProvider)
+		if !ok {
+			errorCount.Inc()
+			log.Printf("Provider %q does not support historical time series\n", providerName)
+			continue
+		}
+
+		symbolsRequestedTotal.WithLabelValues(providerName).Inc()
+
+		closes, err := c.fetchCloses(tsProvider, providerName, symbol, bareSymbol)
+		if err != nil {
+			errorCount.Inc()
+			log.Printf("Error fetching time series for %s: %v\n", symbol, err)
+			continue
+		}
+
+		ls := []string{"symbol", "source", "window"}
+		lvs := []string{bareSymbol, providerName, windowLabel}
+
+		c.emitGauge(ch, "quotes_exporter_sma", "Simple moving average over window.", ls, lvs,
+			func() (float64, error) { return analytics.SMA(closes, c.window) })
+		c.emitGauge(ch, "quotes_exporter_return_pct", "Percent return over window.", ls, lvs,
+			func() (float64, error) { return analytics.ReturnPct(closes, c.window) })
+		c.emitGauge(ch, "quotes_exporter_volatility", "Standard deviation of log returns over window.", ls, lvs,
+			func() (float64, error) { return analytics.Volatility(closes, c.window) })
+		c.emitGauge(ch, "quotes_exporter_max_drawdown", "Largest peak-to-trough percent decline over window.", ls, lvs,
+			func() (float64, error) { return analytics.MaxDrawdown(closes, c.window) })
+	}
+}
+
+// emitGauge computes a single analytics gauge via compute and sends it on
+// ch, logging (without incrementing errorCount) if the window doesn't
+// have enough data yet.
+func (c historyCollector) emitGauge(ch chan<- prometheus.Metric, name, help string, ls, lvs []string, compute func() (float64, error)) {
+	val, err := compute()
+	if err != nil {
+		log.Printf("%s: %v\n", name, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(
+		prometheus.NewDesc(name, help, ls, nil),
+		prometheus.GaugeValue,
+		val,
+		lvs...,
+	)
+}
+
+// fetchCloses returns the chronologically ordered closing prices for
+// symbol, fetching (and caching) the raw bar series if needed. The cache
+// is keyed by symbol, interval and outputsize, so multiple windows that
+// happen to need the same number of bars (the common case, thanks to
+// minSeriesOutputSize) share one upstream call, while a window that needs
+// more bars than a previously cached series holds can't be served that
+// undersized entry for the rest of the cache TTL.
+func (c historyCollector) fetchCloses(tsProvider providers.TimeSeriesProvider, providerName, symbol, bareSymbol string) ([]float64, error) {
+	outputsize := c.window + 1
+	if outputsize < minSeriesOutputSize {
+		outputsize = minSeriesOutputSize
+	}
+
+	cacheKey := fmt.Sprintf("series:%s:%s:%d", c.interval, symbol, outputsize)
+	cachedFetcher := func() (interface{}, error) {
+		return tsProvider.TimeSeries(bareSymbol, c.interval, outputsize)
+	}
+
+	start := time.Now()
+	qret, err, cached := cache.Memoize(cacheKey, cachedFetcher)
+	queryDuration.WithLabelValues(providerName).Observe(float64(time.Since(start).Seconds()))
+	if cached {
+		cacheHitsTotal.Inc()
+	} else {
+		cacheMissesTotal.Inc()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	bars, ok := qret.([]providers.Bar)
+	if !ok {
+		return nil, fmt.Errorf("invalid time series data: %v", qret)
+	}
+
+	closes := make([]float64, len(bars))
+	for i, b := range bars {
+		closes[i] = b.Close
+	}
+	return closes, nil
+}
@@ -0,0 +1,144 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/marcopaganini/quotes-exporter/config"
+	"github.com/marcopaganini/quotes-exporter/providers"
+	"github.com/marcopaganini/quotes-exporter/stonks"
+	"github.com/marcopaganini/quotes-exporter/twelvedata"
+)
+
+func init() {
+	flag.IntVar(&flagPort, "port", config.DefaultPort, "Port to listen on")
+	flag.StringVar(&flagTwelvedataApiKey, "twelvedata-apikey", os.Getenv("TWELVEDATA_API_KEY"), "Twelve Data API key (defaults to $TWELVEDATA_API_KEY)")
+	flag.IntVar(&flagBatchSize, "batch-size", config.DefaultBatchSize, "Maximum number of symbols per batch quote request")
+	flag.StringVar(&flagConfigPath, "config", "", "Path to YAML config file (providers, cache tuning); flags override values it sets")
+}
+
+// loadConfig returns the exporter's configuration, starting from
+// config.Default(), loading flagConfigPath if one was given, and then
+// re-applying any flag the user passed explicitly on the command line so
+// that flags always win over the config file.
+func loadConfig() config.Config {
+	cfg := config.Default()
+	if flagConfigPath != "" {
+		loaded, err := config.Load(flagConfigPath)
+		if err != nil {
+			log.Fatalf("Error loading %s: %v\n", flagConfigPath, err)
+		}
+		cfg = loaded
+	}
+
+	// twelvedata-apikey defaults to $TWELVEDATA_API_KEY, so it must be
+	// applied unconditionally rather than gated on flag.Visit: a user who
+	// only sets the env var (the most common pre-existing deployment) never
+	// passes the flag explicitly, and flag.Visit only calls back for flags
+	// actually passed on the command line.
+	if flagTwelvedataApiKey != "" {
+		pc := cfg.Providers["twelvedata"]
+		pc.APIKey = flagTwelvedataApiKey
+		cfg.Providers["twelvedata"] = pc
+	}
+
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "port":
+			cfg.Port = flagPort
+		case "batch-size":
+			cfg.BatchSize = flagBatchSize
+		}
+	})
+
+	return cfg
+}
+
+// registerProviders wires up every provider enabled in cfg under its
+// provider name, which the short prefixes used in the "symbols" query
+// parameter map to (see providerPrefixes).
+func registerProviders(cfg config.Config) {
+	for name, pc := range cfg.Providers {
+		if !pc.Enabled {
+			continue
+		}
+		switch name {
+		case "twelvedata":
+			var opts []twelvedata.ClientOption
+			if pc.RateLimit > 0 {
+				opts = append(opts, twelvedata.WithRateLimit(pc.RateLimit, 1))
+			}
+			providers.Register("twelvedata", twelvedata.NewProviderWithClient(twelvedata.NewClient(pc.APIKey, opts...)))
+		case "stonks":
+			providers.Register("stonks", stonks.NewProvider())
+		default:
+			log.Printf("Unknown provider %q in config, skipping\n", name)
+		}
+	}
+}
+
+// quotesHandler serves Prometheus metrics for the symbols passed in the
+// request's "symbols" query parameter.
+func quotesHandler(w http.ResponseWriter, r *http.Request) {
+	c, err := newCollector(r.URL)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	registry.MustRegister(staticCollectors...)
+	registry.MustRegister(twelvedata.Metrics()...)
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// historyHandler serves derived time-series metrics (SMA, return,
+// volatility, max drawdown) for the symbols/interval/window passed in the
+// request's query parameters.
+func historyHandler(w http.ResponseWriter, r *http.Request) {
+	c, err := newHistoryCollector(r.URL)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	registry.MustRegister(staticCollectors...)
+	registry.MustRegister(twelvedata.Metrics()...)
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+func main() {
+	flag.Parse()
+
+	cfg := loadConfig()
+	flagBatchSize = cfg.BatchSize
+	initCache(cfg)
+	registerProviders(cfg)
+
+	http.HandleFunc(cfg.Path, quotesHandler)
+	http.HandleFunc("/history", historyHandler)
+
+	addr := fmt.Sprintf(":%d", cfg.Port)
+	log.Printf("Listening on %s\n", addr)
+	log.Fatal(http.ListenAndServe(addr, nil))
+}
@@ -0,0 +1,122 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package providers defines the common interface implemented by every quote
+// backend (Twelve Data, stonks, etc.) and a small registry used by the
+// collector to look providers up by name.
+package providers
+
+import "fmt"
+
+// Quote is the normalized result returned by every provider implementation.
+type Quote struct {
+	Symbol string
+	Price  float64
+}
+
+// Provider is implemented by each data source backend. Quote returns the
+// current price for symbol.
+type Provider interface {
+	Quote(symbol string) (Quote, error)
+}
+
+// FullQuote is the richer result returned by providers that implement
+// FullQuoteProvider, carrying the full set of fields a mature market-data
+// API typically exposes alongside the current price.
+type FullQuote struct {
+	Symbol           string
+	Name             string
+	Exchange         string
+	Currency         string
+	Open             float64
+	High             float64
+	Low              float64
+	Close            float64
+	PreviousClose    float64
+	Volume           float64
+	Change           float64
+	PercentChange    float64
+	FiftyTwoWeekHigh float64
+	FiftyTwoWeekLow  float64
+}
+
+// FullQuoteProvider is an optional interface implemented by providers that
+// can return a richer FullQuote in addition to the plain Quote. The
+// collector type-asserts for it and emits extra gauges when present.
+type FullQuoteProvider interface {
+	FullQuote(symbol string) (FullQuote, error)
+}
+
+// Bar is a single OHLCV entry of a historical time series, in
+// chronological order (oldest first) once returned by a provider.
+type Bar struct {
+	Datetime string
+	Open     float64
+	High     float64
+	Low      float64
+	Close    float64
+	Volume   float64
+}
+
+// TimeSeriesProvider is an optional interface implemented by providers
+// that expose historical OHLCV bars, used to derive metrics like moving
+// averages, returns, volatility and drawdown.
+type TimeSeriesProvider interface {
+	TimeSeries(symbol, interval string, outputsize int) ([]Bar, error)
+}
+
+// BatchProvider is an optional interface implemented by providers whose
+// backend can look several symbols up in a single request. The collector
+// type-asserts for it to avoid burning one API call per symbol per scrape.
+// Implementations return whatever subset of symbols they managed to price;
+// callers treat missing entries as lookup failures for those symbols only.
+type BatchProvider interface {
+	QuoteBatch(symbols []string) (map[string]Quote, error)
+}
+
+// FullQuoteBatchProvider is an optional interface implemented by providers
+// whose backend can return FullQuote for several symbols in a single
+// request. The collector type-asserts for it and prefers it over
+// FullQuoteProvider so a scrape doesn't serialize one full-quote call per
+// symbol. Implementations return whatever subset of symbols they managed
+// to resolve; callers treat missing entries as lookup failures for those
+// symbols only.
+type FullQuoteBatchProvider interface {
+	FullQuoteBatch(symbols []string) (map[string]FullQuote, error)
+}
+
+// registry holds all providers registered via Register, keyed by name.
+var registry = map[string]Provider{}
+
+// Register adds a named provider implementation to the registry. The
+// exporter's main package calls it at startup for each enabled provider.
+func Register(name string, p Provider) {
+	registry[name] = p
+}
+
+// Get returns the registered provider for name, or an error if no provider
+// was registered under that name.
+func Get(name string) (Provider, error) {
+	p, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("no provider registered for %q", name)
+	}
+	return p, nil
+}
+
+// Names returns the names of all registered providers.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	return names
+}
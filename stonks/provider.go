@@ -0,0 +1,35 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package stonks
+
+import (
+	"strings"
+
+	"github.com/marcopaganini/quotes-exporter/providers"
+)
+
+// Provider implements providers.Provider on top of the stonks package.
+type Provider struct{}
+
+// NewProvider returns a stonks provider. Stonks requires no API key.
+func NewProvider() *Provider {
+	return &Provider{}
+}
+
+// Quote returns the current price for symbol.
+func (p *Provider) Quote(symbol string) (providers.Quote, error) {
+	price, err := Quote(symbol)
+	if err != nil {
+		return providers.Quote{}, err
+	}
+	return providers.Quote{Symbol: strings.ToUpper(symbol), Price: price}, nil
+}
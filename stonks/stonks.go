@@ -0,0 +1,77 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package stonks fetches quotes from Yahoo Finance's public chart API. It
+// requires no API key, which makes it a convenient second data source for
+// symbols Twelve Data doesn't cover.
+package stonks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const stonksURL = "https://query1.finance.yahoo.com/v8/finance/chart/%s"
+
+// chartResponse mirrors the relevant fields of Yahoo Finance's chart API
+// response.
+type chartResponse struct {
+	Chart struct {
+		Result []struct {
+			Meta struct {
+				RegularMarketPrice float64 `json:"regularMarketPrice"`
+			} `json:"meta"`
+		} `json:"result"`
+		Error *struct {
+			Description string `json:"description"`
+		} `json:"error"`
+	} `json:"chart"`
+}
+
+// Quote returns the current price for symbol.
+func Quote(symbol string) (float64, error) {
+	symbol = strings.ToUpper(symbol)
+
+	resp, err := http.Get(fmt.Sprintf(stonksURL, symbol))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %d for %s", resp.StatusCode, symbol)
+	}
+
+	var data chartResponse
+	if err := json.Unmarshal(body, &data); err != nil {
+		return 0, fmt.Errorf("error parsing JSON: %w", err)
+	}
+	if data.Chart.Error != nil {
+		return 0, fmt.Errorf("yahoo finance error for %s: %s", symbol, data.Chart.Error.Description)
+	}
+	if len(data.Chart.Result) == 0 {
+		return 0, fmt.Errorf("no quote data returned for %s", symbol)
+	}
+
+	price := data.Chart.Result[0].Meta.RegularMarketPrice
+	if price == 0 {
+		return 0, fmt.Errorf("regularMarketPrice is not included in JSON for %s", symbol)
+	}
+
+	return price, nil
+}
@@ -0,0 +1,187 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package twelvedata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// defaultQPS and defaultBurst match Twelve Data's free tier limit of
+	// 8 requests per minute.
+	defaultQPS        = 8.0 / 60.0
+	defaultBurst      = 1
+	defaultMaxRetries = 5
+	baseBackoff       = 250 * time.Millisecond
+)
+
+// providerLabel is the value used for the "provider" label on the shared
+// provider_* metrics below.
+const providerLabel = "twelvedata"
+
+var (
+	rateLimitedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "quotes_exporter_provider_rate_limited_total",
+			Help: "Count of requests that were rejected with a rate-limit error, by provider.",
+		},
+		[]string{"provider"},
+	)
+	retriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "quotes_exporter_provider_retries_total",
+			Help: "Count of request retries after a rate-limit or server error, by provider.",
+		},
+		[]string{"provider"},
+	)
+)
+
+// Metrics returns the collectors that should be registered by the caller
+// (quotes_exporter_provider_rate_limited_total and
+// quotes_exporter_provider_retries_total).
+func Metrics() []prometheus.Collector {
+	return []prometheus.Collector{rateLimitedTotal, retriesTotal}
+}
+
+// apiError mirrors the JSON error envelope Twelve Data returns on failure,
+// e.g. {"code":429,"message":"..."}.
+type apiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Client talks to the Twelve Data API, rate-limiting outgoing requests and
+// retrying rate-limit/server errors with exponential backoff and jitter.
+type Client struct {
+	apikey     string
+	httpClient *http.Client
+	limiter    *rate.Limiter
+	maxRetries int
+}
+
+// ClientOption configures a Client constructed by NewClient.
+type ClientOption func(*Client)
+
+// WithRateLimit overrides the default 8 requests/minute rate limit.
+func WithRateLimit(qps float64, burst int) ClientOption {
+	return func(c *Client) {
+		c.limiter = rate.NewLimiter(rate.Limit(qps), burst)
+	}
+}
+
+// WithMaxRetries overrides the default number of retry attempts.
+func WithMaxRetries(n int) ClientOption {
+	return func(c *Client) {
+		c.maxRetries = n
+	}
+}
+
+// NewClient returns a Twelve Data API client authenticated with apikey.
+func NewClient(apikey string, opts ...ClientOption) *Client {
+	c := &Client{
+		apikey:     apikey,
+		httpClient: http.DefaultClient,
+		limiter:    rate.NewLimiter(rate.Limit(defaultQPS), defaultBurst),
+		maxRetries: defaultMaxRetries,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// get fetches url, honoring the client's rate limit and retrying
+// rate-limit (HTTP 429, or a {"code":429,...} envelope) and server (5xx)
+// errors with exponential backoff plus jitter. The rate limiter only gates
+// the initial request: retries are already paced by backoff(), so waiting
+// on the limiter again on every attempt would needlessly stack a fresh
+// ~QPS-interval wait on top of it and could block a scrape for tens of
+// seconds over a handful of retries.
+func (c *Client) get(url string) ([]byte, error) {
+	if err := c.limiter.Wait(context.Background()); err != nil {
+		return nil, fmt.Errorf("rate limiter: %w", err)
+	}
+
+	var lastErr error
+	attempts := 0
+
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		attempts++
+		body, retryable, err := c.fetch(url)
+		if err == nil {
+			return body, nil
+		}
+		lastErr = err
+		if !retryable || attempt == c.maxRetries {
+			break
+		}
+
+		retriesTotal.WithLabelValues(providerLabel).Inc()
+		time.Sleep(backoff(attempt))
+	}
+
+	return nil, fmt.Errorf("giving up after %d attempt(s): %w", attempts, lastErr)
+}
+
+// fetch performs a single HTTP GET and classifies the outcome: it reports
+// whether the error (if any) is worth retrying.
+func (c *Client) fetch(url string) (body []byte, retryable bool, err error) {
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, err
+	}
+
+	// Twelve Data reports some errors (rate limits included) with HTTP
+	// 200 and a {"code":429,"message":"..."} envelope, so the body is
+	// always inspected on top of the status code.
+	var ae apiError
+	_ = json.Unmarshal(body, &ae)
+
+	status := resp.StatusCode
+	if ae.Code != 0 {
+		status = ae.Code
+	}
+
+	switch {
+	case status == http.StatusTooManyRequests:
+		rateLimitedTotal.WithLabelValues(providerLabel).Inc()
+		return nil, true, fmt.Errorf("rate limited (429): %s", ae.Message)
+	case status >= 500:
+		return nil, true, fmt.Errorf("server error (%d): %s", status, ae.Message)
+	case resp.StatusCode != http.StatusOK:
+		return nil, false, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, ae.Message)
+	}
+
+	return body, false, nil
+}
+
+// backoff returns the exponential backoff (plus jitter) to wait before
+// retry number attempt (0-indexed).
+func backoff(attempt int) time.Duration {
+	d := baseBackoff << attempt
+	return d + time.Duration(rand.Int63n(int64(d)))
+}
@@ -0,0 +1,134 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package twelvedata
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// newTestClient returns a Client pointed at srv with a rate limit high
+// enough that it doesn't slow the test down, and maxRetries retries.
+func newTestClient(srv *httptest.Server, maxRetries int) *Client {
+	c := NewClient("testkey", WithRateLimit(1000, 1000), WithMaxRetries(maxRetries))
+	c.httpClient = srv.Client()
+	return c
+}
+
+func TestGetRetriesOn429ThenSucceeds(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.Write([]byte(`{"code":429,"message":"limit exceeded"}`))
+			return
+		}
+		w.Write([]byte(`{"price":"123.45"}`))
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv, 5)
+	body, err := c.get(srv.URL)
+	if err != nil {
+		t.Fatalf("get() returned error: %v", err)
+	}
+	var data price
+	if err := json.Unmarshal(body, &data); err != nil {
+		t.Fatalf("unmarshaling response: %v", err)
+	}
+	if data.Price != "123.45" {
+		t.Errorf("get() price = %q, want %q", data.Price, "123.45")
+	}
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Errorf("handler called %d times, want 3 (2 failures + 1 success)", got)
+	}
+}
+
+func TestGetGivesUpAfterMaxRetries(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte(`{"code":429,"message":"limit exceeded"}`))
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv, 2)
+	if _, err := c.get(srv.URL); err == nil {
+		t.Fatal("get() against a persistently rate-limited server: want error, got nil")
+	}
+	if got, want := atomic.LoadInt32(&calls), int32(3); got != want {
+		t.Errorf("handler called %d times, want %d (1 initial + maxRetries)", got, want)
+	}
+}
+
+func TestGetDoesNotRetryNonRetryableStatus(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv, 5)
+	if _, err := c.get(srv.URL); err == nil {
+		t.Fatal("get() against a 400 response: want error, got nil")
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("handler called %d times, want 1 (no retry on a non-retryable status)", got)
+	}
+}
+
+// withTestURL temporarily overrides twelvedataURL for a test and restores
+// it afterwards, since the client builds request URLs from the package-level
+// format string rather than taking a base URL.
+func withTestURL(t *testing.T, url string) {
+	t.Helper()
+	orig := twelvedataURL
+	twelvedataURL = url
+	t.Cleanup(func() { twelvedataURL = orig })
+}
+
+func TestQuoteBatchSingleSymbolShape(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"price":"50.00"}`))
+	}))
+	defer srv.Close()
+	withTestURL(t, srv.URL+"?symbol=%s&apikey=%s")
+
+	c := newTestClient(srv, 0)
+	got, err := c.QuoteBatch([]string{"AAPL"})
+	if err != nil {
+		t.Fatalf("QuoteBatch() returned error: %v", err)
+	}
+	if want := 50.0; got["AAPL"] != want {
+		t.Errorf("QuoteBatch()[AAPL] = %v, want %v", got["AAPL"], want)
+	}
+}
+
+func TestQuoteBatchMultiSymbolShape(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"AAPL":{"price":"50.00"},"MSFT":{"price":"60.00"}}`))
+	}))
+	defer srv.Close()
+	withTestURL(t, srv.URL+"?symbol=%s&apikey=%s")
+
+	c := newTestClient(srv, 0)
+	got, err := c.QuoteBatch([]string{"AAPL", "MSFT"})
+	if err != nil {
+		t.Fatalf("QuoteBatch() returned error: %v", err)
+	}
+	if len(got) != 2 || got["AAPL"] != 50.0 || got["MSFT"] != 60.0 {
+		t.Errorf("QuoteBatch() = %v, want map[AAPL:50 MSFT:60]", got)
+	}
+}
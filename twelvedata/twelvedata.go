@@ -14,52 +14,361 @@ package twelvedata
 import (
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"strconv"
 	"strings"
+
+	"github.com/marcopaganini/quotes-exporter/providers"
 )
 
-const (
-	twelvedataURL = "https://api.twelvedata.com/price?symbol=%s&apikey=%s"
+// These are vars rather than consts so tests can point the client at an
+// httptest.Server.
+var (
+	twelvedataURL       = "https://api.twelvedata.com/price?symbol=%s&apikey=%s"
+	twelvedataQuoteURL  = "https://api.twelvedata.com/quote?symbol=%s&apikey=%s"
+	twelvedataSeriesURL = "https://api.twelvedata.com/time_series?symbol=%s&interval=%s&outputsize=%d&apikey=%s"
 )
 
 type price struct {
 	Price string `json:"price"`
 }
 
+// fiftyTwoWeek holds the 52-week high/low block nested in the /quote
+// response.
+type fiftyTwoWeek struct {
+	High string `json:"high"`
+	Low  string `json:"low"`
+}
+
+// quote mirrors the relevant fields of the Twelve Data /quote response.
+// Like the rest of the API, every numeric value is encoded as a string.
+type quote struct {
+	Name          string       `json:"name"`
+	Exchange      string       `json:"exchange"`
+	Currency      string       `json:"currency"`
+	Open          string       `json:"open"`
+	High          string       `json:"high"`
+	Low           string       `json:"low"`
+	Close         string       `json:"close"`
+	PreviousClose string       `json:"previous_close"`
+	Volume        string       `json:"volume"`
+	Change        string       `json:"change"`
+	PercentChange string       `json:"percent_change"`
+	FiftyTwoWeek  fiftyTwoWeek `json:"fifty_two_week"`
+}
+
 // Quote returns the current value of a symbol.
-func Quote(symbol string, apikey string) (float64, error) {
+func (c *Client) Quote(symbol string) (float64, error) {
 	symbol = strings.ToUpper(symbol)
 
-	resp, err := http.Get(fmt.Sprintf(twelvedataURL, symbol, apikey))
+	body, err := c.get(fmt.Sprintf(twelvedataURL, symbol, c.apikey))
 	if err != nil {
 		return 0, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	var data price
+	if err := json.Unmarshal(body, &data); err != nil {
+		return 0, fmt.Errorf("error parsing JSON: %w", err)
+	}
+	if data.Price == "" {
+		return 0, fmt.Errorf("price is not included in JSON for %s", symbol)
+	}
+
+	price, err := strconv.ParseFloat(data.Price, 64)
 	if err != nil {
-		return 0, err
+		return 0, fmt.Errorf("error when type conversion string to float64: %w", err)
 	}
 
-	var data price
-	err = json.Unmarshal([]byte(body), &data)
+	return price, nil
+}
+
+// QuoteBatch returns the current value of several symbols in a single
+// request, keyed by the uppercased symbol. Twelve Data accepts a
+// comma-separated symbol list on the same /price endpoint used by Quote,
+// and returns a map keyed by symbol when more than one is requested, or
+// the single-symbol shape otherwise; both are handled here.
+func (c *Client) QuoteBatch(symbols []string) (map[string]float64, error) {
+	upper := make([]string, len(symbols))
+	for i, s := range symbols {
+		upper[i] = strings.ToUpper(s)
+	}
+
+	body, err := c.get(fmt.Sprintf(twelvedataURL, strings.Join(upper, ","), c.apikey))
 	if err != nil {
-		fmt.Println("Error parsing JSON:", err)
-		return 0, err
+		return nil, err
 	}
 
-	if data.Price == "" {
-		fmt.Println("Price is not included in JSON:", err)
-		return 0, err
+	result := make(map[string]float64, len(upper))
+
+	// A single symbol gets back the plain {"price": "..."} shape rather
+	// than a map keyed by symbol.
+	if len(upper) == 1 {
+		var data price
+		if err := json.Unmarshal(body, &data); err != nil {
+			return nil, fmt.Errorf("error parsing JSON: %w", err)
+		}
+		if data.Price == "" {
+			return nil, fmt.Errorf("price is not included in JSON for %s", upper[0])
+		}
+		v, err := strconv.ParseFloat(data.Price, 64)
+		if err != nil {
+			return nil, fmt.Errorf("error when type conversion string to float64: %w", err)
+		}
+		result[upper[0]] = v
+		return result, nil
 	}
 
-	price, err := strconv.ParseFloat(data.Price, 64)
+	var data map[string]price
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("error parsing JSON: %w", err)
+	}
+	for symbol, p := range data {
+		if p.Price == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(p.Price, 64)
+		if err != nil {
+			continue
+		}
+		result[strings.ToUpper(symbol)] = v
+	}
+	return result, nil
+}
+
+// FullQuote returns the full set of fields available for symbol from the
+// Twelve Data /quote endpoint (name, exchange, currency, OHLC, volume,
+// change and 52-week range).
+func (c *Client) FullQuote(symbol string) (providers.FullQuote, error) {
+	symbol = strings.ToUpper(symbol)
+
+	body, err := c.get(fmt.Sprintf(twelvedataQuoteURL, symbol, c.apikey))
 	if err != nil {
-		fmt.Println("Error when type conversion string to float64:", err)
-		return 0, err
+		return providers.FullQuote{}, err
 	}
 
-	return price, nil
+	var data quote
+	if err := json.Unmarshal(body, &data); err != nil {
+		return providers.FullQuote{}, fmt.Errorf("error parsing JSON: %w", err)
+	}
+
+	return quoteToFullQuote(symbol, data)
+}
+
+// FullQuoteBatch returns FullQuote for several symbols in a single request,
+// keyed by the uppercased symbol. Twelve Data accepts a comma-separated
+// symbol list on the same /quote endpoint used by FullQuote, returning a
+// map keyed by symbol when more than one is requested, or the single-quote
+// shape otherwise; both are handled here, mirroring QuoteBatch.
+func (c *Client) FullQuoteBatch(symbols []string) (map[string]providers.FullQuote, error) {
+	upper := make([]string, len(symbols))
+	for i, s := range symbols {
+		upper[i] = strings.ToUpper(s)
+	}
+
+	body, err := c.get(fmt.Sprintf(twelvedataQuoteURL, strings.Join(upper, ","), c.apikey))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]providers.FullQuote, len(upper))
+
+	// A single symbol gets back the plain quote shape rather than a map
+	// keyed by symbol.
+	if len(upper) == 1 {
+		var data quote
+		if err := json.Unmarshal(body, &data); err != nil {
+			return nil, fmt.Errorf("error parsing JSON: %w", err)
+		}
+		fq, err := quoteToFullQuote(upper[0], data)
+		if err != nil {
+			return nil, err
+		}
+		result[upper[0]] = fq
+		return result, nil
+	}
+
+	var data map[string]quote
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("error parsing JSON: %w", err)
+	}
+	for symbol, q := range data {
+		symbol = strings.ToUpper(symbol)
+		fq, err := quoteToFullQuote(symbol, q)
+		if err != nil {
+			continue
+		}
+		result[symbol] = fq
+	}
+	return result, nil
+}
+
+// quoteToFullQuote converts the raw (string-encoded) quote response for
+// symbol into a providers.FullQuote.
+func quoteToFullQuote(symbol string, data quote) (providers.FullQuote, error) {
+	if data.Close == "" {
+		return providers.FullQuote{}, fmt.Errorf("close price not included in JSON for %s", symbol)
+	}
+
+	fq := providers.FullQuote{
+		Symbol:   symbol,
+		Name:     data.Name,
+		Exchange: data.Exchange,
+		Currency: data.Currency,
+	}
+
+	fields := []struct {
+		raw string
+		dst *float64
+	}{
+		{data.Open, &fq.Open},
+		{data.High, &fq.High},
+		{data.Low, &fq.Low},
+		{data.Close, &fq.Close},
+		{data.PreviousClose, &fq.PreviousClose},
+		{data.Volume, &fq.Volume},
+		{data.Change, &fq.Change},
+		{data.PercentChange, &fq.PercentChange},
+		{data.FiftyTwoWeek.High, &fq.FiftyTwoWeekHigh},
+		{data.FiftyTwoWeek.Low, &fq.FiftyTwoWeekLow},
+	}
+	for _, f := range fields {
+		if f.raw == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(f.raw, 64)
+		if err != nil {
+			return providers.FullQuote{}, fmt.Errorf("error when type conversion string to float64: %w", err)
+		}
+		*f.dst = v
+	}
+
+	return fq, nil
+}
+
+// bar mirrors a single entry of the Twelve Data /time_series response.
+type bar struct {
+	Datetime string `json:"datetime"`
+	Open     string `json:"open"`
+	High     string `json:"high"`
+	Low      string `json:"low"`
+	Close    string `json:"close"`
+	Volume   string `json:"volume"`
+}
+
+// timeSeriesResponse mirrors the Twelve Data /time_series response.
+type timeSeriesResponse struct {
+	Values []bar `json:"values"`
+}
+
+// TimeSeries returns outputsize historical OHLCV bars for symbol at the
+// given interval (e.g. "1day"), oldest first. Twelve Data returns bars
+// newest first; TimeSeries reverses them into chronological order so
+// callers (and the analytics package) can work with a plain, ordered
+// slice of closes.
+func (c *Client) TimeSeries(symbol, interval string, outputsize int) ([]providers.Bar, error) {
+	symbol = strings.ToUpper(symbol)
+
+	body, err := c.get(fmt.Sprintf(twelvedataSeriesURL, symbol, interval, outputsize, c.apikey))
+	if err != nil {
+		return nil, err
+	}
+
+	var data timeSeriesResponse
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("error parsing JSON: %w", err)
+	}
+	if len(data.Values) == 0 {
+		return nil, fmt.Errorf("no time series values returned for %s", symbol)
+	}
+
+	bars := make([]providers.Bar, len(data.Values))
+	for i, v := range data.Values {
+		b := providers.Bar{Datetime: v.Datetime}
+		fields := []struct {
+			raw string
+			dst *float64
+		}{
+			{v.Open, &b.Open},
+			{v.High, &b.High},
+			{v.Low, &b.Low},
+			{v.Close, &b.Close},
+			{v.Volume, &b.Volume},
+		}
+		for _, f := range fields {
+			if f.raw == "" {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(f.raw, 64)
+			if err != nil {
+				return nil, fmt.Errorf("error when type conversion string to float64: %w", err)
+			}
+			*f.dst = parsed
+		}
+		// Twelve Data returns values newest-first; reverse into
+		// chronological order.
+		bars[len(data.Values)-1-i] = b
+	}
+
+	return bars, nil
+}
+
+// Provider implements providers.Provider (and the optional
+// providers.FullQuoteProvider / providers.BatchProvider interfaces) on top
+// of a rate-limited, retrying Twelve Data Client.
+type Provider struct {
+	client *Client
+}
+
+// NewProvider returns a Twelve Data provider authenticated with apikey,
+// using the client's default rate limit and retry settings.
+func NewProvider(apikey string) *Provider {
+	return &Provider{client: NewClient(apikey)}
+}
+
+// NewProviderWithClient returns a Twelve Data provider backed by an
+// already-configured Client, e.g. one built with WithRateLimit or
+// WithMaxRetries.
+func NewProviderWithClient(client *Client) *Provider {
+	return &Provider{client: client}
+}
+
+// Quote returns the current price for symbol.
+func (p *Provider) Quote(symbol string) (providers.Quote, error) {
+	price, err := p.client.Quote(symbol)
+	if err != nil {
+		return providers.Quote{}, err
+	}
+	return providers.Quote{Symbol: strings.ToUpper(symbol), Price: price}, nil
+}
+
+// FullQuote returns the full set of fields available for symbol. It
+// implements providers.FullQuoteProvider.
+func (p *Provider) FullQuote(symbol string) (providers.FullQuote, error) {
+	return p.client.FullQuote(symbol)
+}
+
+// FullQuoteBatch returns the full set of fields available for several
+// symbols in a single request. It implements
+// providers.FullQuoteBatchProvider.
+func (p *Provider) FullQuoteBatch(symbols []string) (map[string]providers.FullQuote, error) {
+	return p.client.FullQuoteBatch(symbols)
+}
+
+// TimeSeries returns outputsize historical OHLCV bars for symbol. It
+// implements providers.TimeSeriesProvider.
+func (p *Provider) TimeSeries(symbol, interval string, outputsize int) ([]providers.Bar, error) {
+	return p.client.TimeSeries(symbol, interval, outputsize)
+}
+
+// QuoteBatch returns the current price for several symbols in a single
+// request. It implements providers.BatchProvider.
+func (p *Provider) QuoteBatch(symbols []string) (map[string]providers.Quote, error) {
+	prices, err := p.client.QuoteBatch(symbols)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]providers.Quote, len(prices))
+	for symbol, price := range prices {
+		result[symbol] = providers.Quote{Symbol: symbol, Price: price}
+	}
+	return result, nil
 }